@@ -0,0 +1,248 @@
+package zenoss
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	// HostNameField is an optional metadata field containing the detected
+	// host name of the machine the exporter is running on.
+	HostNameField = "host.name"
+
+	// CloudProviderField is an optional metadata field identifying the
+	// detected cloud provider, e.g. "gcp" or "aws".
+	CloudProviderField = "cloud.provider"
+
+	// CloudRegionField is an optional metadata field containing the
+	// detected cloud region.
+	CloudRegionField = "cloud.region"
+
+	// CloudAvailabilityZoneField is an optional metadata field containing
+	// the detected cloud availability zone.
+	CloudAvailabilityZoneField = "cloud.availability_zone"
+
+	// K8sNodeField is an optional metadata field containing a Kubernetes
+	// node name.
+	K8sNodeField = "k8s.node"
+
+	// ContainerNameField is an optional metadata field containing a
+	// container name.
+	ContainerNameField = "container.name"
+)
+
+// ResourceDetector detects metadata fields describing the environment the
+// exporter is running in, e.g. cloud or Kubernetes metadata. Detect should
+// fail soft: a non-nil error means "not applicable here" rather than a
+// fatal condition, and NewExporter logs it at debug level and continues.
+type ResourceDetector interface {
+	Detect(ctx context.Context) (map[string]string, error)
+}
+
+// DefaultResourceDetectors is used when Options.ResourceDetectors is nil.
+// Each detector fails soft when its environment isn't present, so it's
+// safe to run all of them unconditionally.
+var DefaultResourceDetectors = []ResourceDetector{
+	GCEResourceDetector{},
+	EC2ResourceDetector{},
+	KubernetesResourceDetector{},
+}
+
+// detectResources runs each detector in order and merges the attributes it
+// finds into dst, later detectors overwriting earlier ones. A detector
+// that errors contributes nothing.
+func (e *Exporter) detectResources(ctx context.Context, detectors []ResourceDetector, dst map[string]string) map[string]string {
+	for _, detector := range detectors {
+		attrs, err := detector.Detect(ctx)
+		if err != nil {
+			e.log(
+				LogLevelDebug,
+				map[string]interface{}{"error": err, "detector": fmt.Sprintf("%T", detector)},
+				"resource detector found nothing")
+			continue
+		}
+
+		if dst == nil {
+			dst = map[string]string{}
+		}
+
+		for k, v := range attrs {
+			dst[k] = v
+		}
+	}
+
+	return dst
+}
+
+// GCEResourceDetector detects host.name, cloud.region, and
+// cloud.availability_zone when running on Google Compute Engine or GKE, by
+// querying the GCE metadata server.
+type GCEResourceDetector struct{}
+
+func (GCEResourceDetector) Detect(ctx context.Context) (map[string]string, error) {
+	name, err := gceMetadata(ctx, "instance/name")
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := gceMetadata(ctx, "instance/zone")
+	if err != nil {
+		return nil, err
+	}
+	zone = zone[strings.LastIndex(zone, "/")+1:]
+
+	attrs := map[string]string{
+		HostNameField:              name,
+		CloudProviderField:         "gcp",
+		CloudAvailabilityZoneField: zone,
+	}
+
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		attrs[CloudRegionField] = zone[:i]
+	}
+
+	return attrs, nil
+}
+
+func gceMetadata(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://metadata.google.internal/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	return doMetadataRequest(req)
+}
+
+// EC2ResourceDetector detects host.name, cloud.region, and
+// cloud.availability_zone when running on an AWS EC2 instance, using the
+// IMDSv2 instance metadata service.
+type EC2ResourceDetector struct{}
+
+func (EC2ResourceDetector) Detect(ctx context.Context) (map[string]string, error) {
+	token, err := ec2MetadataToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	az, err := ec2Metadata(ctx, token, "meta-data/placement/availability-zone")
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]string{
+		CloudProviderField:         "aws",
+		CloudAvailabilityZoneField: az,
+	}
+
+	if len(az) > 1 {
+		attrs[CloudRegionField] = az[:len(az)-1]
+	}
+
+	if hostname, err := ec2Metadata(ctx, token, "meta-data/hostname"); err == nil {
+		attrs[HostNameField] = hostname
+	}
+
+	return attrs, nil
+}
+
+func ec2MetadataToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut,
+		"http://169.254.169.254/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+
+	return doMetadataRequest(req)
+}
+
+func ec2Metadata(ctx context.Context, token, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://169.254.169.254/latest/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+
+	return doMetadataRequest(req)
+}
+
+func doMetadataRequest(req *http.Request) (string, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata request to %s: status %d", req.URL, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+// serviceAccountNamespaceFile is where Kubernetes mounts a pod's
+// namespace alongside its service-account token.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// KubernetesResourceDetector detects k8s.namespace, k8s.pod, k8s.node,
+// k8s.cluster, and container.name when running inside a Kubernetes pod.
+// Namespace comes from the namespace file mounted alongside the
+// service-account token; the rest rely on
+// the downward API conventionally exposing pod/node identity as
+// environment variables, since Kubernetes has no built-in equivalent.
+type KubernetesResourceDetector struct{}
+
+func (KubernetesResourceDetector) Detect(ctx context.Context) (map[string]string, error) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return nil, fmt.Errorf("KUBERNETES_SERVICE_HOST not set")
+	}
+
+	attrs := map[string]string{}
+
+	if namespace, err := ioutil.ReadFile(serviceAccountNamespaceFile); err == nil {
+		attrs[K8sNamespaceField] = strings.TrimSpace(string(namespace))
+	}
+
+	if pod := firstNonEmptyEnv("POD_NAME", "HOSTNAME"); pod != "" {
+		attrs[K8sPodField] = pod
+	}
+
+	if node := firstNonEmptyEnv("NODE_NAME"); node != "" {
+		attrs[K8sNodeField] = node
+	}
+
+	if cluster := firstNonEmptyEnv("CLUSTER_NAME", "K8S_CLUSTER_NAME"); cluster != "" {
+		attrs[K8sClusterField] = cluster
+	}
+
+	if container := firstNonEmptyEnv("CONTAINER_NAME"); container != "" {
+		attrs[ContainerNameField] = container
+	}
+
+	if len(attrs) == 0 {
+		return nil, fmt.Errorf("no kubernetes attributes detected")
+	}
+
+	return attrs, nil
+}
+
+func firstNonEmptyEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,279 @@
+package zenoss
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultInitialInterval is the default RetrySettings.InitialInterval.
+const DefaultInitialInterval = 5 * time.Second
+
+// DefaultMaxInterval is the default RetrySettings.MaxInterval.
+const DefaultMaxInterval = 30 * time.Second
+
+// DefaultMaxElapsedTime is the default RetrySettings.MaxElapsedTime.
+const DefaultMaxElapsedTime = 5 * time.Minute
+
+// DefaultQueueSize is the default QueueSettings.QueueSize.
+const DefaultQueueSize = 1000
+
+// DefaultNumConsumers is the default QueueSettings.NumConsumers.
+const DefaultNumConsumers = 4
+
+// RetrySettings configures the exponential backoff used to retry
+// PutModels/PutMetrics requests that fail with a retryable gRPC error.
+// It has no effect unless QueueSettings.Enabled is also true.
+type RetrySettings struct {
+	// InitialInterval is the backoff duration used before the first retry.
+	// Default: 5s
+	InitialInterval time.Duration
+
+	// MaxInterval caps the backoff duration between retries.
+	// Default: 30s
+	MaxInterval time.Duration
+
+	// MaxElapsedTime is the maximum amount of time a bundle may spend being
+	// retried before it's dropped. Zero uses the default.
+	// Default: 5m
+	MaxElapsedTime time.Duration
+}
+
+// QueueSettings configures the in-memory retry queue that PutModels and
+// PutMetrics requests pass through before being dropped.
+type QueueSettings struct {
+	// Enabled turns on the retry queue. When false, a failed request is
+	// logged and dropped, as it always has been.
+	Enabled bool
+
+	// QueueSize is the maximum number of bundles that may be queued for
+	// (re)delivery at once. Additional bundles are dropped.
+	// Default: 1000
+	QueueSize int
+
+	// NumConsumers is the number of goroutines concurrently pulling
+	// bundles off the queue and sending them to Zenoss.
+	// Default: 4
+	NumConsumers int
+}
+
+// ExporterStats holds point-in-time counters describing retry queue
+// back-pressure. See Exporter.ExporterStats.
+type ExporterStats struct {
+	ModelsSent    uint64
+	ModelsDropped uint64
+	ModelsRetried uint64
+	ModelsQueued  int
+
+	MetricsSent    uint64
+	MetricsDropped uint64
+	MetricsRetried uint64
+	MetricsQueued  int
+}
+
+// retryQueue is a persistent, in-memory FIFO of send jobs. Jobs that fail
+// with a retryable error are re-enqueued after an exponential backoff
+// delay; jobs that fail with a non-retryable error, or that have been
+// retried past RetrySettings.MaxElapsedTime, are dropped.
+type retryQueue struct {
+	label    string
+	retry    RetrySettings
+	queue    chan *retryJob
+	sent     uint64
+	dropped  uint64
+	retried  uint64
+	wg       sync.WaitGroup
+	quit     chan struct{}
+	quitOnce sync.Once
+	log      logFunc
+}
+
+// retryJob is a single unit of work queued for (re)delivery.
+type retryJob struct {
+	enqueuedAt time.Time
+	attempt    int
+	send       func(ctx context.Context) error
+}
+
+// logFunc matches the signature of Exporter.log, allowing retryQueue to log
+// without depending on the Exporter type itself.
+type logFunc func(level LogLevel, fields map[string]interface{}, format string, args ...interface{})
+
+func newRetryQueue(label string, qs QueueSettings, rs RetrySettings, log logFunc) *retryQueue {
+	size := qs.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+
+	numConsumers := qs.NumConsumers
+	if numConsumers <= 0 {
+		numConsumers = DefaultNumConsumers
+	}
+
+	q := &retryQueue{
+		label: label,
+		retry: rs,
+		queue: make(chan *retryJob, size),
+		quit:  make(chan struct{}),
+		log:   log,
+	}
+
+	for i := 0; i < numConsumers; i++ {
+		q.wg.Add(1)
+		go q.consume()
+	}
+
+	return q
+}
+
+// submit queues send for delivery, dropping it immediately if the queue is
+// full.
+func (q *retryQueue) submit(send func(ctx context.Context) error) {
+	q.enqueue(&retryJob{enqueuedAt: time.Now(), send: send})
+}
+
+func (q *retryQueue) enqueue(job *retryJob) {
+	select {
+	case q.queue <- job:
+	default:
+		atomic.AddUint64(&q.dropped, 1)
+		q.log(
+			LogLevelError,
+			map[string]interface{}{"queue": q.label},
+			"dropping bundle: queue full")
+	}
+}
+
+func (q *retryQueue) consume() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case job, ok := <-q.queue:
+			if !ok {
+				return
+			}
+			q.process(job)
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+func (q *retryQueue) process(job *retryJob) {
+	err := job.send(context.Background())
+	if err == nil {
+		atomic.AddUint64(&q.sent, 1)
+		return
+	}
+
+	if !isRetryableError(err) {
+		atomic.AddUint64(&q.dropped, 1)
+		q.log(
+			LogLevelError,
+			map[string]interface{}{"error": err, "queue": q.label},
+			"dropping bundle: non-retryable error")
+		return
+	}
+
+	maxElapsedTime := q.retry.MaxElapsedTime
+	if maxElapsedTime == 0 {
+		maxElapsedTime = DefaultMaxElapsedTime
+	}
+
+	if time.Since(job.enqueuedAt) > maxElapsedTime {
+		atomic.AddUint64(&q.dropped, 1)
+		q.log(
+			LogLevelError,
+			map[string]interface{}{"error": err, "queue": q.label},
+			"dropping bundle: exceeded max elapsed time")
+		return
+	}
+
+	job.attempt++
+	delay := q.backoff(job.attempt)
+
+	atomic.AddUint64(&q.retried, 1)
+	q.log(
+		LogLevelWarning,
+		map[string]interface{}{"error": err, "queue": q.label, "attempt": job.attempt, "delay": delay},
+		"retrying bundle after error")
+
+	time.AfterFunc(delay, func() {
+		q.enqueue(job)
+	})
+}
+
+// backoff returns the delay before the given retry attempt (1-indexed),
+// as an exponentially increasing interval with full jitter applied.
+func (q *retryQueue) backoff(attempt int) time.Duration {
+	initial := q.retry.InitialInterval
+	if initial <= 0 {
+		initial = DefaultInitialInterval
+	}
+
+	max := q.retry.MaxInterval
+	if max <= 0 {
+		max = DefaultMaxInterval
+	}
+
+	delay := initial * time.Duration(1<<uint(attempt-1))
+	if delay <= 0 || delay > max {
+		delay = max
+	}
+
+	// Full jitter, as recommended by AWS's backoff/jitter guidance: a
+	// random value in [0, delay) rather than a fixed or decorrelated one.
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+func (q *retryQueue) depth() int {
+	return len(q.queue)
+}
+
+func (q *retryQueue) stats() (sent, dropped, retried uint64, depth int) {
+	return atomic.LoadUint64(&q.sent), atomic.LoadUint64(&q.dropped), atomic.LoadUint64(&q.retried), q.depth()
+}
+
+// stop signals the consumer goroutines to exit and waits for them to do so,
+// then drains any jobs still sitting in the queue, counting each as
+// dropped rather than abandoning it silently. Jobs already scheduled for a
+// delayed re-enqueue via time.AfterFunc (see process) that land after stop
+// has drained the queue are not accounted for; this is a best-effort count
+// for the common case of stopping with a mostly-idle retry schedule.
+func (q *retryQueue) stop() {
+	q.quitOnce.Do(func() {
+		close(q.quit)
+	})
+	q.wg.Wait()
+
+	for {
+		select {
+		case job := <-q.queue:
+			atomic.AddUint64(&q.dropped, 1)
+			q.log(
+				LogLevelWarning,
+				map[string]interface{}{"queue": q.label, "attempt": job.attempt},
+				"dropping bundle: queue stopped before delivery")
+		default:
+			return
+		}
+	}
+}
+
+// isRetryableError reports whether err is a transient gRPC error worth
+// retrying, as opposed to one that will never succeed (e.g. a bad request
+// or bad credentials).
+func isRetryableError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
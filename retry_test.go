@@ -0,0 +1,77 @@
+package zenoss
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"unavailable", status.Error(codes.Unavailable, "down"), true},
+		{"resource exhausted", status.Error(codes.ResourceExhausted, "quota"), true},
+		{"deadline exceeded", status.Error(codes.DeadlineExceeded, "timeout"), true},
+		{"not found", status.Error(codes.NotFound, "missing"), false},
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad"), false},
+		{"non-grpc error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableError(tt.err); got != tt.want {
+				t.Errorf("isRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryQueueBackoff(t *testing.T) {
+	q := &retryQueue{
+		retry: RetrySettings{
+			InitialInterval: 1 * time.Second,
+			MaxInterval:     10 * time.Second,
+		},
+	}
+
+	// attempt 1: delay should be in [0, InitialInterval).
+	for i := 0; i < 50; i++ {
+		d := q.backoff(1)
+		if d < 0 || d >= q.retry.InitialInterval {
+			t.Fatalf("backoff(1) = %v, want in [0, %v)", d, q.retry.InitialInterval)
+		}
+	}
+
+	// attempt 4: 1s * 2^3 = 8s, still under the 10s cap.
+	for i := 0; i < 50; i++ {
+		d := q.backoff(4)
+		if d < 0 || d >= 8*time.Second {
+			t.Fatalf("backoff(4) = %v, want in [0, %v)", d, 8*time.Second)
+		}
+	}
+
+	// A large attempt overflows time.Duration's exponent math; backoff must
+	// fall back to MaxInterval rather than wrapping into a negative delay.
+	for i := 0; i < 50; i++ {
+		d := q.backoff(100)
+		if d < 0 || d >= q.retry.MaxInterval {
+			t.Fatalf("backoff(100) = %v, want in [0, %v)", d, q.retry.MaxInterval)
+		}
+	}
+}
+
+func TestRetryQueueBackoffDefaults(t *testing.T) {
+	q := &retryQueue{}
+
+	d := q.backoff(1)
+	if d < 0 || d >= DefaultInitialInterval {
+		t.Errorf("backoff(1) with zero-value RetrySettings = %v, want in [0, %v)", d, DefaultInitialInterval)
+	}
+}
@@ -0,0 +1,201 @@
+package zenoss
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+
+	zenoss "github.com/zenoss/zenoss-protobufs/go/cloud/data_receiver"
+)
+
+const (
+	// SpanNameField is the model/metric metadata field for a span's name.
+	SpanNameField = "span.name"
+
+	// SpanKindField is the model/metric metadata field for a span's kind.
+	SpanKindField = "span.kind"
+
+	// SpanStatusCodeField is the model/metric metadata field for a span's
+	// status code.
+	SpanStatusCodeField = "span.status.code"
+
+	// SpanStatusMessageField is the optional model/metric metadata field
+	// for a span's status message.
+	SpanStatusMessageField = "span.status.message"
+
+	// TraceIDField is the model/metric metadata field for the ID of the
+	// trace a span belongs to.
+	TraceIDField = "trace.id"
+
+	// SpanIDField is the model/metric metadata field for a span's ID.
+	SpanIDField = "span.id"
+
+	// DurationMetric is the name of the metric emitted for a span's
+	// duration, in milliseconds.
+	DurationMetric = "duration.ms"
+
+	// MessageEventMetricPrefix prefixes the metric emitted for each
+	// trace.MessageEvent on a span.
+	MessageEventMetricPrefix = "span.message_event"
+)
+
+var (
+	// Ensure we implement trace.Exporter interface.
+	_ trace.Exporter = (*Exporter)(nil)
+)
+
+// ExportSpan exports sd to Zenoss as a model (the entity the span ran
+// against) and a duration.ms metric, plus one counter metric per
+// MessageEvent. Satisfies the trace.Exporter interface, so it can be
+// registered alongside ExportView with trace.RegisterExporter.
+func (e *Exporter) ExportSpan(sd *trace.SpanData) {
+	e.log(
+		LogLevelDebug,
+		logrus.Fields{"spanName": sd.Name, "traceId": sd.TraceID.String()},
+		"exporting span")
+
+	var nameParts []string
+	dimensions := map[string]string{}
+	metadataFields := map[string]string{SourceTypeField: DefaultSourceType}
+
+	for _, modelDimensionTag := range e.options.ModelDimensionTags {
+		if attr, ok := sd.Attributes[modelDimensionTag]; ok {
+			value := fmt.Sprintf("%v", attr)
+			dimensions[modelDimensionTag] = value
+			nameParts = append(nameParts, value)
+		}
+	}
+
+	// Any attribute that isn't a dimension becomes metadata.
+	for key, attr := range sd.Attributes {
+		if _, exists := dimensions[key]; !exists {
+			metadataFields[key] = fmt.Sprintf("%v", attr)
+		}
+	}
+
+	metadataFields[SpanNameField] = sd.Name
+	metadataFields[SpanKindField] = spanKindString(sd.SpanKind)
+	metadataFields[SpanStatusCodeField] = strconv.FormatInt(int64(sd.Status.Code), 10)
+	if sd.Status.Message != "" {
+		metadataFields[SpanStatusMessageField] = sd.Status.Message
+	}
+	metadataFields[TraceIDField] = sd.TraceID.String()
+	metadataFields[SpanIDField] = sd.SpanID.String()
+
+	if from, to := spanImpacts(sd); from != "" || to != "" {
+		if from != "" {
+			metadataFields[ImpactFromDimensionsField] = from
+		}
+		if to != "" {
+			metadataFields[ImpactToDimensionsField] = to
+		}
+	}
+
+	// Copy global dimensions.
+	for k, v := range e.options.GlobalDimensions {
+		dimensions[k] = v
+	}
+
+	// Copy global metadata fields.
+	for k, v := range e.options.GlobalMetadataFields {
+		metadataFields[k] = v
+	}
+
+	if len(nameParts) == 0 {
+		nameParts = []string{sd.Name}
+	}
+
+	data := &Data{}
+
+	timestamp := sd.EndTime.UnixNano() / 1e6
+
+	modelMetadataFields := copyMap(metadataFields)
+	modelMetadataFields[NameField] = strings.Join(nameParts, "/")
+
+	data.AddModel(&zenoss.Model{
+		Timestamp:      timestamp,
+		Dimensions:     dimensions,
+		MetadataFields: metadataFieldsFromMap(modelMetadataFields),
+	})
+
+	data.AddMetric(&zenoss.Metric{
+		Metric:         DurationMetric,
+		Dimensions:     copyMap(dimensions),
+		MetadataFields: metadataFieldsFromMap(copyMap(metadataFields)),
+		Timestamp:      timestamp,
+		Value:          float64(sd.EndTime.Sub(sd.StartTime)) / float64(time.Millisecond),
+	})
+
+	for _, messageEvent := range sd.MessageEvents {
+		data.AddMetric(&zenoss.Metric{
+			Metric:         fmt.Sprintf("%s.%s", MessageEventMetricPrefix, messageEventTypeString(messageEvent.EventType)),
+			Dimensions:     copyMap(dimensions),
+			MetadataFields: metadataFieldsFromMap(copyMap(metadataFields)),
+			Timestamp:      messageEvent.Time.UnixNano() / 1e6,
+			Value:          1,
+		})
+	}
+
+	e.bundleData(data)
+}
+
+// spanImpacts translates sd's parent span and any span.Links into
+// ImpactFromDimensions/ImpactToDimensions values, mirroring how
+// addKubernetesImpacts expresses impact relationships as "field=value"
+// strings. from identifies what impacts sd (its parent, plus any
+// LinkTypeParent links); to identifies what sd impacts (its children, via
+// LinkTypeChild links). Each entity each field references must itself set
+// SpanIDField on its own model/metric for Zenoss to resolve the edge.
+//
+// A span may have more than one child/parent link, so from/to can each
+// carry multiple "field=value" entries joined by ";" -
+// metadataFieldsFromMap splits on that separator to build the
+// Impact*DimensionsField list, one entry per linked entity.
+func spanImpacts(sd *trace.SpanData) (from, to string) {
+	var froms, tos []string
+
+	if sd.ParentSpanID != (trace.SpanID{}) {
+		froms = append(froms, fmt.Sprintf("%s=%s", SpanIDField, sd.ParentSpanID.String()))
+	}
+
+	for _, link := range sd.Links {
+		dim := fmt.Sprintf("%s=%s", SpanIDField, link.SpanID.String())
+
+		switch link.Type {
+		case trace.LinkTypeChild:
+			// The linked span is a child of sd: sd impacts it.
+			tos = append(tos, dim)
+		case trace.LinkTypeParent:
+			// The linked span is sd's parent: it impacts sd.
+			froms = append(froms, dim)
+		}
+	}
+
+	return strings.Join(froms, ";"), strings.Join(tos, ";")
+}
+
+func spanKindString(kind int) string {
+	switch kind {
+	case trace.SpanKindClient:
+		return "client"
+	case trace.SpanKindServer:
+		return "server"
+	default:
+		return "unspecified"
+	}
+}
+
+func messageEventTypeString(eventType trace.MessageEventType) string {
+	switch eventType {
+	case trace.MessageEventTypeSent:
+		return "sent"
+	case trace.MessageEventTypeRecv:
+		return "recv"
+	default:
+		return "unspecified"
+	}
+}
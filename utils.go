@@ -1,6 +1,50 @@
 package zenoss
 
-import structpb "github.com/golang/protobuf/ptypes/struct"
+import (
+	"strings"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	zenoss "github.com/zenoss/zenoss-protobufs/go/cloud/data_receiver"
+)
+
+// chunkModels splits models into slices of at most size models each, so
+// each slice can be sent as its own PutModels RPC.
+func chunkModels(models []*zenoss.Model, size int) [][]*zenoss.Model {
+	if size <= 0 || len(models) <= size {
+		return [][]*zenoss.Model{models}
+	}
+
+	chunks := make([][]*zenoss.Model, 0, (len(models)+size-1)/size)
+	for i := 0; i < len(models); i += size {
+		end := i + size
+		if end > len(models) {
+			end = len(models)
+		}
+		chunks = append(chunks, models[i:end])
+	}
+
+	return chunks
+}
+
+// chunkMetrics splits metrics into slices of at most size metrics each, so
+// each slice can be sent as its own PutMetrics RPC.
+func chunkMetrics(metrics []*zenoss.Metric, size int) [][]*zenoss.Metric {
+	if size <= 0 || len(metrics) <= size {
+		return [][]*zenoss.Metric{metrics}
+	}
+
+	chunks := make([][]*zenoss.Metric, 0, (len(metrics)+size-1)/size)
+	for i := 0; i < len(metrics); i += size {
+		end := i + size
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+		chunks = append(chunks, metrics[i:end])
+	}
+
+	return chunks
+}
 
 func copyMap(m map[string]string) map[string]string {
 	newMap := make(map[string]string)
@@ -15,7 +59,10 @@ func metadataFieldsFromMap(m map[string]string) *structpb.Struct {
 
 	for k, v := range m {
 		if k == ImpactFromDimensionsField || k == ImpactToDimensionsField {
-			fields[k] = valueFromStringSlice([]string{v})
+			// A span can have multiple child/parent links, so spanImpacts
+			// joins them with ";"; addKubernetesImpacts never emits one,
+			// so this always yields a single-entry list for it.
+			fields[k] = valueFromStringSlice(strings.Split(v, ";"))
 		} else {
 			fields[k] = valueFromString(v)
 		}
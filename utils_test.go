@@ -0,0 +1,95 @@
+package zenoss
+
+import (
+	"testing"
+
+	zenoss "github.com/zenoss/zenoss-protobufs/go/cloud/data_receiver"
+)
+
+func TestChunkModels(t *testing.T) {
+	newModels := func(n int) []*zenoss.Model {
+		models := make([]*zenoss.Model, n)
+		for i := range models {
+			models[i] = &zenoss.Model{}
+		}
+		return models
+	}
+
+	tests := []struct {
+		name       string
+		numModels  int
+		size       int
+		wantChunks []int // length of each expected chunk
+	}{
+		{"empty", 0, 10, []int{0}},
+		{"size zero returns single chunk", 5, 0, []int{5}},
+		{"size negative returns single chunk", 5, -1, []int{5}},
+		{"fewer than size", 3, 10, []int{3}},
+		{"exactly size", 10, 10, []int{10}},
+		{"one more than size", 11, 10, []int{10, 1}},
+		{"multiple full chunks", 20, 10, []int{10, 10}},
+		{"size one", 3, 1, []int{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkModels(newModels(tt.numModels), tt.size)
+
+			if len(chunks) != len(tt.wantChunks) {
+				t.Fatalf("got %d chunks, want %d", len(chunks), len(tt.wantChunks))
+			}
+
+			total := 0
+			for i, chunk := range chunks {
+				if len(chunk) != tt.wantChunks[i] {
+					t.Errorf("chunk %d: got %d models, want %d", i, len(chunk), tt.wantChunks[i])
+				}
+				total += len(chunk)
+			}
+
+			if total != tt.numModels {
+				t.Errorf("chunks contained %d models total, want %d", total, tt.numModels)
+			}
+		})
+	}
+}
+
+func TestChunkMetrics(t *testing.T) {
+	newMetrics := func(n int) []*zenoss.Metric {
+		metrics := make([]*zenoss.Metric, n)
+		for i := range metrics {
+			metrics[i] = &zenoss.Metric{}
+		}
+		return metrics
+	}
+
+	tests := []struct {
+		name       string
+		numMetrics int
+		size       int
+		wantChunks []int
+	}{
+		{"empty", 0, 10, []int{0}},
+		{"size zero returns single chunk", 5, 0, []int{5}},
+		{"fewer than size", 3, 10, []int{3}},
+		{"exactly size", 10, 10, []int{10}},
+		{"one more than size", 11, 10, []int{10, 1}},
+		{"multiple full chunks", 20, 10, []int{10, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkMetrics(newMetrics(tt.numMetrics), tt.size)
+
+			if len(chunks) != len(tt.wantChunks) {
+				t.Fatalf("got %d chunks, want %d", len(chunks), len(tt.wantChunks))
+			}
+
+			for i, chunk := range chunks {
+				if len(chunk) != tt.wantChunks[i] {
+					t.Errorf("chunk %d: got %d metrics, want %d", i, len(chunk), tt.wantChunks[i])
+				}
+			}
+		})
+	}
+}
@@ -7,10 +7,13 @@ import (
 	"github.com/sirupsen/logrus"
 	"log"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"go.opencensus.io/metric/metricdata"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
 	"google.golang.org/api/support/bundler"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -40,6 +43,12 @@ const (
 
 	// UnitsField is the optional metric metadata field containing a metric's unit of measure.
 	UnitsField = "units"
+
+	// DefaultMaxModelsPerRequest is the default Options.MaxModelsPerRequest.
+	DefaultMaxModelsPerRequest = 1000
+
+	// DefaultMaxMetricsPerRequest is the default Options.MaxMetricsPerRequest.
+	DefaultMaxMetricsPerRequest = 1000
 )
 
 type LogLevel int8
@@ -51,6 +60,26 @@ const (
 	LogLevelError   = 4
 )
 
+// HistogramMode controls how view.DistributionData is translated into
+// zenoss.Metric values.
+type HistogramMode int8
+
+const (
+	// HistogramSummary exports the five count/min/max/mean/ss scalar
+	// sub-metrics. This is the default, for backwards compatibility.
+	HistogramSummary HistogramMode = iota
+
+	// HistogramBuckets exports one metric per distribution bucket, with
+	// "le" (upper bound) and "bucket_index" dimensions distinguishing
+	// each bucket's datapoint, so percentiles can be reconstructed
+	// downstream.
+	HistogramBuckets
+
+	// HistogramBoth exports both the summary sub-metrics and the
+	// per-bucket metrics.
+	HistogramBoth
+)
+
 var (
 	// Ensure we implement view.Explorer interface.
 	_ view.Exporter = (*Exporter)(nil)
@@ -112,6 +141,49 @@ type Options struct {
 	// OnLog is a function that can be used to supply custom logging logic.
 	// Optional.
 	OnLog func(level LogLevel, fields map[string]interface{}, format string, args ...interface{})
+
+	// QueueSettings enables and configures an in-memory retry queue that
+	// PutModels/PutMetrics requests pass through instead of being dropped
+	// on the first failure.
+	// Optional.
+	QueueSettings QueueSettings
+
+	// RetrySettings configures the backoff used to retry requests queued
+	// by QueueSettings. Has no effect unless QueueSettings.Enabled.
+	// Optional.
+	RetrySettings RetrySettings
+
+	// MaxModelsPerRequest caps the number of models sent in a single
+	// PutModels RPC. Bundles larger than this are split into multiple
+	// RPCs.
+	// Default: 1000
+	MaxModelsPerRequest int
+
+	// MaxMetricsPerRequest caps the number of metrics sent in a single
+	// PutMetrics RPC. Bundles larger than this are split into multiple
+	// RPCs.
+	// Default: 1000
+	MaxMetricsPerRequest int
+
+	// MaxCallSendMsgSize sets the gRPC client's maximum send message size,
+	// in bytes, via grpc.MaxCallSendMsgSize.
+	// Default: grpc-go's default (4 MiB)
+	MaxCallSendMsgSize int
+
+	// ResourceDetectors run once at NewExporter time. Any attributes they
+	// detect (e.g. host.name, cloud.provider, k8s.cluster) are merged into
+	// GlobalMetadataFields, so addKubernetesImpacts and similar defaults
+	// work without per-deployment boilerplate.
+	// Default: GCEResourceDetector, EC2ResourceDetector, and
+	// KubernetesResourceDetector. Set to an empty, non-nil slice to
+	// disable resource detection entirely.
+	ResourceDetectors []ResourceDetector
+
+	// HistogramMode controls how view.DistributionData values are
+	// exported: as five summary scalars (HistogramSummary, the default),
+	// one metric per bucket (HistogramBuckets), or both (HistogramBoth).
+	// Optional.
+	HistogramMode HistogramMode
 }
 
 type Exporter struct {
@@ -121,6 +193,8 @@ type Exporter struct {
 	modelsBundler         *bundler.Bundler
 	metricsBundler        *bundler.Bundler
 	modelFreshnessChecker *freshnessChecker
+	modelsQueue           *retryQueue
+	metricsQueue          *retryQueue
 }
 
 func NewExporter(options Options) (*Exporter, error) {
@@ -138,8 +212,16 @@ func NewExporter(options Options) (*Exporter, error) {
 		}
 	}
 
-	opt := grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
-	conn, err := grpc.Dial(options.Address, opt)
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})),
+	}
+
+	if options.MaxCallSendMsgSize > 0 {
+		dialOptions = append(dialOptions, grpc.WithDefaultCallOptions(
+			grpc.MaxCallSendMsgSize(options.MaxCallSendMsgSize)))
+	}
+
+	conn, err := grpc.Dial(options.Address, dialOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -175,15 +257,68 @@ func NewExporter(options Options) (*Exporter, error) {
 		e.metricsBundler.BundleCountThreshold = 1000
 	}
 
+	if e.options.QueueSettings.Enabled {
+		e.modelsQueue = newRetryQueue("models", e.options.QueueSettings, e.options.RetrySettings, e.log)
+		e.metricsQueue = newRetryQueue("metrics", e.options.QueueSettings, e.options.RetrySettings, e.log)
+	}
+
+	resourceDetectors := options.ResourceDetectors
+	if resourceDetectors == nil {
+		resourceDetectors = DefaultResourceDetectors
+	}
+
+	detectCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	e.options.GlobalMetadataFields = e.detectResources(
+		detectCtx, resourceDetectors, e.options.GlobalMetadataFields)
+
 	return e, nil
 }
 
-// Flush waits for exported data to be sent.
+// ExporterStats returns point-in-time counters describing the retry
+// queue's back-pressure. It's only meaningful when QueueSettings.Enabled.
+func (e *Exporter) ExporterStats() ExporterStats {
+	var stats ExporterStats
+
+	if e.modelsQueue != nil {
+		stats.ModelsSent, stats.ModelsDropped, stats.ModelsRetried, stats.ModelsQueued = e.modelsQueue.stats()
+	}
+
+	if e.metricsQueue != nil {
+		stats.MetricsSent, stats.MetricsDropped, stats.MetricsRetried, stats.MetricsQueued = e.metricsQueue.stats()
+	}
+
+	return stats
+}
+
+// Flush waits for buffered data to be handed off for sending. When
+// QueueSettings.Enabled, this only waits for bundles to reach the retry
+// queue, not for the queue to actually deliver them; use ExporterStats to
+// observe delivery, or Close to stop and account for anything left queued.
 func (e *Exporter) Flush() {
 	e.modelsBundler.Flush()
 	e.metricsBundler.Flush()
 }
 
+// Close flushes any buffered data, then stops the retry queue's consumer
+// goroutines, if QueueSettings.Enabled. Any bundle still queued at that
+// point is dropped and counted in ExporterStats rather than delivered; a
+// bundle whose delayed retry re-enters the queue after Close is neither
+// delivered nor counted. Call Close before discarding an Exporter to avoid
+// leaking those goroutines.
+func (e *Exporter) Close() {
+	e.Flush()
+
+	if e.modelsQueue != nil {
+		e.modelsQueue.stop()
+	}
+
+	if e.metricsQueue != nil {
+		e.metricsQueue.stop()
+	}
+}
+
 // ExportView exports stats to Zenoss.
 func (e *Exporter) ExportView(viewData *view.Data) {
 	e.log(
@@ -275,9 +410,6 @@ func (e *Exporter) onViewRow(viewData *view.Data, viewRow *view.Row) {
 		}
 	}
 
-	// Add impact fields specific to Kubernetes applications.
-	addKubernetesImpacts(metadataFields)
-
 	// Copy global dimensions.
 	for k, v := range e.options.GlobalDimensions {
 		dimensions[k] = v
@@ -288,6 +420,11 @@ func (e *Exporter) onViewRow(viewData *view.Data, viewRow *view.Row) {
 		metadataFields[k] = v
 	}
 
+	// Add impact fields specific to Kubernetes applications. This runs
+	// after GlobalMetadataFields is copied in so that cluster/namespace/pod
+	// values merged in by ResourceDetectors are visible here too.
+	addKubernetesImpacts(metadataFields)
+
 	// Row may not have any tags in EntityTagKeys. Nothing to be done for that.
 	if len(nameParts) == 0 {
 		return
@@ -307,7 +444,12 @@ func (e *Exporter) onViewRow(viewData *view.Data, viewRow *view.Row) {
 		MetadataFields: metadataFieldsFromMap(modelMetadataFields),
 	})
 
-	addMetric := func(name string, value float64) {
+	addMetric := func(name string, value float64, extraDimensions, extraMetadataFields map[string]string) {
+		metricDimensions := copyMap(dimensions)
+		for k, v := range extraDimensions {
+			metricDimensions[k] = v
+		}
+
 		metricMetadataFields := copyMap(metadataFields)
 
 		description := viewData.View.Description
@@ -320,9 +462,13 @@ func (e *Exporter) onViewRow(viewData *view.Data, viewRow *view.Row) {
 			metricMetadataFields[UnitsField] = units
 		}
 
+		for k, v := range extraMetadataFields {
+			metricMetadataFields[k] = v
+		}
+
 		data.AddMetric(&zenoss.Metric{
 			Metric:         name,
-			Dimensions:     copyMap(dimensions),
+			Dimensions:     metricDimensions,
 			MetadataFields: metadataFieldsFromMap(metricMetadataFields),
 			Timestamp:      timestamp,
 			Value:          value,
@@ -331,22 +477,58 @@ func (e *Exporter) onViewRow(viewData *view.Data, viewRow *view.Row) {
 
 	switch rowData := viewRow.Data.(type) {
 	case *view.CountData:
-		addMetric(viewData.View.Name, float64(rowData.Value))
+		addMetric(viewData.View.Name, float64(rowData.Value), nil, nil)
 	case *view.SumData:
-		addMetric(viewData.View.Name, rowData.Value)
+		addMetric(viewData.View.Name, rowData.Value, nil, nil)
 	case *view.LastValueData:
-		addMetric(viewData.View.Name, rowData.Value)
+		addMetric(viewData.View.Name, rowData.Value, nil, nil)
 	case *view.DistributionData:
-		params := map[string]float64{
-			"count": float64(rowData.Count),
-			"min":   rowData.Min,
-			"max":   rowData.Max,
-			"mean":  rowData.Mean,
-			"ss":    rowData.SumOfSquaredDev,
+		histogramMode := e.options.HistogramMode
+
+		if histogramMode != HistogramBuckets {
+			params := map[string]float64{
+				"count": float64(rowData.Count),
+				"min":   rowData.Min,
+				"max":   rowData.Max,
+				"mean":  rowData.Mean,
+				"ss":    rowData.SumOfSquaredDev,
+			}
+
+			for suffix, value := range params {
+				addMetric(fmt.Sprintf("%s/%s", viewData.View.Name, suffix), value, nil, nil)
+			}
 		}
 
-		for suffix, value := range params {
-			addMetric(fmt.Sprintf("%s/%s", viewData.View.Name, suffix), value)
+		if histogramMode == HistogramBuckets || histogramMode == HistogramBoth {
+			bounds := viewData.View.Aggregation.Buckets
+
+			for i, bucketCount := range rowData.CountPerBucket {
+				// bucket_index/le go in Dimensions, not MetadataFields:
+				// a datapoint's identity is (metric, dimensions[,
+				// timestamp]), so without this every bucket in a row
+				// would collapse onto the same series.
+				bucketDimensions := map[string]string{"bucket_index": strconv.Itoa(i)}
+
+				if i < len(bounds) {
+					bucketDimensions["le"] = strconv.FormatFloat(bounds[i], 'g', -1, 64)
+				} else {
+					bucketDimensions["le"] = "+Inf"
+				}
+
+				var exemplarMetadata map[string]string
+				if i < len(rowData.ExemplarsPerBucket) {
+					if exemplar := rowData.ExemplarsPerBucket[i]; exemplar != nil {
+						if sc, ok := exemplar.Attachments[metricdata.AttachmentKeySpanContext].(trace.SpanContext); ok {
+							exemplarMetadata = map[string]string{
+								TraceIDField: sc.TraceID.String(),
+								SpanIDField:  sc.SpanID.String(),
+							}
+						}
+					}
+				}
+
+				addMetric(fmt.Sprintf("%s/bucket", viewData.View.Name), float64(bucketCount), bucketDimensions, exemplarMetadata)
+			}
 		}
 	}
 
@@ -404,7 +586,78 @@ func (e *Exporter) bundleData(data *Data) {
 }
 
 func (e *Exporter) putModels(models []*zenoss.Model) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	maxPerRequest := e.options.MaxModelsPerRequest
+	if maxPerRequest <= 0 {
+		maxPerRequest = DefaultMaxModelsPerRequest
+	}
+
+	chunks := chunkModels(models, maxPerRequest)
+
+	if e.modelsQueue != nil {
+		for _, chunk := range chunks {
+			chunk := chunk
+			e.modelsQueue.submit(func(ctx context.Context) error {
+				return e.sendModelsChunk(ctx, chunk)
+			})
+		}
+		return
+	}
+
+	var failed int32
+	for _, chunk := range chunks {
+		chunkFailed, err := e.sendModels(context.Background(), chunk)
+		if err != nil {
+			e.log(
+				LogLevelError,
+				logrus.Fields{"error": err, "models": len(chunk)},
+				"unable to send models")
+			continue
+		}
+		failed += chunkFailed
+	}
+
+	if failed > 0 {
+		e.log(
+			LogLevelWarning,
+			logrus.Fields{"models": len(models), "failed": failed},
+			"failed to send models")
+	} else {
+		e.log(
+			LogLevelDebug,
+			logrus.Fields{"models": len(models)},
+			"sent models")
+	}
+}
+
+// sendModelsChunk sends a single chunk of a larger models bundle and logs
+// the outcome. It's used as the unit of work queued by e.modelsQueue, so
+// each chunk is retried independently of its siblings.
+func (e *Exporter) sendModelsChunk(ctx context.Context, models []*zenoss.Model) error {
+	failed, err := e.sendModels(ctx, models)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		e.log(
+			LogLevelWarning,
+			logrus.Fields{"models": len(models), "failed": failed},
+			"failed to send models")
+	} else {
+		e.log(
+			LogLevelDebug,
+			logrus.Fields{"models": len(models)},
+			"sent models")
+	}
+
+	return nil
+}
+
+// sendModels makes a single PutModels RPC and returns the number of models
+// the API reported as failed. A non-nil error indicates the RPC itself
+// failed (as opposed to individual models within it).
+func (e *Exporter) sendModels(ctx context.Context, models []*zenoss.Model) (int32, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 	defer cancel()
 
 	ctx = metadata.AppendToOutgoingContext(ctx, APIKeyHeader, e.options.APIKey)
@@ -413,29 +666,86 @@ func (e *Exporter) putModels(models []*zenoss.Model) {
 		DetailedResponse: true,
 		Models:           models,
 	})
+	if err != nil {
+		return 0, err
+	}
+
+	return modelStatus.GetFailed(), nil
+}
+
+func (e *Exporter) putMetrics(metrics []*zenoss.Metric) {
+	maxPerRequest := e.options.MaxMetricsPerRequest
+	if maxPerRequest <= 0 {
+		maxPerRequest = DefaultMaxMetricsPerRequest
+	}
+
+	chunks := chunkMetrics(metrics, maxPerRequest)
+
+	if e.metricsQueue != nil {
+		for _, chunk := range chunks {
+			chunk := chunk
+			e.metricsQueue.submit(func(ctx context.Context) error {
+				return e.sendMetricsChunk(ctx, chunk)
+			})
+		}
+		return
+	}
+
+	var failed int32
+	for _, chunk := range chunks {
+		chunkFailed, err := e.sendMetrics(context.Background(), chunk)
+		if err != nil {
+			e.log(
+				LogLevelError,
+				logrus.Fields{"error": err, "metrics": len(chunk)},
+				"unable to send metrics")
+			continue
+		}
+		failed += chunkFailed
+	}
+
+	if failed > 0 {
+		e.log(
+			LogLevelWarning,
+			logrus.Fields{"metrics": len(metrics), "failed": failed},
+			"failed to send metrics")
+	} else {
+		e.log(
+			LogLevelDebug,
+			logrus.Fields{"metrics": len(metrics)},
+			"sent metrics")
+	}
+}
 
+// sendMetricsChunk sends a single chunk of a larger metrics bundle and logs
+// the outcome. It's used as the unit of work queued by e.metricsQueue, so
+// each chunk is retried independently of its siblings.
+func (e *Exporter) sendMetricsChunk(ctx context.Context, metrics []*zenoss.Metric) error {
+	failed, err := e.sendMetrics(ctx, metrics)
 	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
 		e.log(
-			LogLevelError,
-			logrus.Fields{"error": err, "models": len(models)},
-			"unable to send models")
+			LogLevelWarning,
+			logrus.Fields{"metrics": len(metrics), "failed": failed},
+			"failed to send metrics")
 	} else {
-		if modelStatus.GetFailed() > 0 {
-			e.log(
-				LogLevelWarning,
-				logrus.Fields{"error": err, "models": len(models), "failed": modelStatus.GetFailed()},
-				"failed to send models")
-		} else {
-			e.log(
-				LogLevelDebug,
-				logrus.Fields{"models": len(models)},
-				"sent models")
-		}
+		e.log(
+			LogLevelDebug,
+			logrus.Fields{"metrics": len(metrics)},
+			"sent metrics")
 	}
+
+	return nil
 }
 
-func (e *Exporter) putMetrics(metrics []*zenoss.Metric) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+// sendMetrics makes a single PutMetrics RPC and returns the number of
+// metrics the API reported as failed. A non-nil error indicates the RPC
+// itself failed (as opposed to individual metrics within it).
+func (e *Exporter) sendMetrics(ctx context.Context, metrics []*zenoss.Metric) (int32, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 	defer cancel()
 
 	ctx = metadata.AppendToOutgoingContext(ctx, APIKeyHeader, e.options.APIKey)
@@ -444,25 +754,11 @@ func (e *Exporter) putMetrics(metrics []*zenoss.Metric) {
 		DetailedResponse: true,
 		Metrics:          metrics,
 	})
-
 	if err != nil {
-		e.log(
-			LogLevelError,
-			logrus.Fields{"error": err, "metrics": len(metrics)},
-			"unable to send metrics")
-	} else {
-		if metricStatus.GetFailed() > 0 {
-			e.log(
-				LogLevelWarning,
-				logrus.Fields{"error": err, "metrics": len(metrics), "failed": metricStatus.GetFailed()},
-				"failed to send metrics")
-		} else {
-			e.log(
-				LogLevelDebug,
-				logrus.Fields{"metrics": len(metrics)},
-				"sent metrics")
-		}
+		return 0, err
 	}
+
+	return metricStatus.GetFailed(), nil
 }
 
 func (e *Exporter) log(level LogLevel, fields map[string]interface{}, format string, args ...interface{}) {
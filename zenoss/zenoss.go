@@ -3,8 +3,11 @@ package zenoss
 import (
 	"context"
 	"fmt"
+	"strconv"
 
+	"go.opencensus.io/metric/metricdata"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
 
 	"github.com/zenoss/zenoss-protobufs/go/cloud/data_receiver"
 )
@@ -24,6 +27,14 @@ const (
 
 	// UnitsField is the optional tag containing a metric's unit of measure.
 	UnitsField = "units"
+
+	// TraceIDField is the optional tag identifying the trace an exemplar
+	// was recorded on.
+	TraceIDField = "trace.id"
+
+	// SpanIDField is the optional tag identifying the span an exemplar
+	// was recorded on.
+	SpanIDField = "span.id"
 )
 
 var (
@@ -48,8 +59,32 @@ type Options struct {
 
 	// ExtraTags is a map of extra tags to add to every metric.
 	ExtraTags map[string]string
+
+	// HistogramMode controls how view.DistributionData values are
+	// exported: as five summary scalars (HistogramSummary, the default),
+	// one metric per bucket (HistogramBuckets), or both (HistogramBoth).
+	HistogramMode HistogramMode
 }
 
+// HistogramMode controls how view.DistributionData is translated into
+// TaggedMetric values.
+type HistogramMode int8
+
+const (
+	// HistogramSummary exports the five count/min/max/mean/ss scalar
+	// sub-metrics. This is the default, for backwards compatibility.
+	HistogramSummary HistogramMode = iota
+
+	// HistogramBuckets exports one metric per distribution bucket, with
+	// "le" (upper bound) and "bucket_index" tags, so percentiles can be
+	// reconstructed downstream.
+	HistogramBuckets
+
+	// HistogramBoth exports both the summary sub-metrics and the
+	// per-bucket metrics.
+	HistogramBoth
+)
+
 type Exporter struct {
 	options Options
 	output  data_receiver.DataReceiverServiceClient
@@ -89,6 +124,17 @@ func (e *Exporter) Flush() {
 	}
 }
 
+// copyTags returns a shallow copy of tags, so a metric's tag map can be
+// mutated (e.g. with per-bucket "le"/"bucket_index" values) without
+// affecting other metrics built from the same row.
+func copyTags(tags map[string]string) map[string]string {
+	newTags := make(map[string]string, len(tags))
+	for k, v := range tags {
+		newTags[k] = v
+	}
+	return newTags
+}
+
 // ExportView exports stats to Zenoss. Satisfies view.Exporter interface.
 func (e *Exporter) ExportView(viewData *view.Data) {
 	metrics := make([]*data_receiver.TaggedMetric, 0, 5)
@@ -143,19 +189,55 @@ func (e *Exporter) ExportView(viewData *view.Data) {
 		case *view.LastValueData:
 			addMetric(viewData.View.Name, rowData.Value, tags)
 		case *view.DistributionData:
-			params := map[string]float64{
-				"count": float64(rowData.Count),
-				"min":   rowData.Min,
-				"max":   rowData.Max,
-				"mean":  rowData.Mean,
-				"ss":    rowData.SumOfSquaredDev,
+			if e.options.HistogramMode != HistogramBuckets {
+				params := map[string]float64{
+					"count": float64(rowData.Count),
+					"min":   rowData.Min,
+					"max":   rowData.Max,
+					"mean":  rowData.Mean,
+					"ss":    rowData.SumOfSquaredDev,
+				}
+
+				for suffix, value := range params {
+					addMetric(
+						fmt.Sprintf("%s/%s", viewData.View.Name, suffix),
+						value,
+						copyTags(tags))
+				}
 			}
 
-			for suffix, value := range params {
-				addMetric(
-					fmt.Sprintf("%s/%s", viewData.View.Name, suffix),
-					value,
-					tags)
+			if e.options.HistogramMode == HistogramBuckets || e.options.HistogramMode == HistogramBoth {
+				bounds := viewData.View.Aggregation.Buckets
+
+				for i, bucketCount := range rowData.CountPerBucket {
+					// TaggedMetric has a single Tags map, not separate
+					// dimension/metadata fields, so bucket_index/le must
+					// go here to give each bucket's datapoint a distinct
+					// identity; a shared name/tags/timestamp would
+					// collapse every bucket onto one series.
+					bucketTags := copyTags(tags)
+					bucketTags["bucket_index"] = strconv.Itoa(i)
+
+					if i < len(bounds) {
+						bucketTags["le"] = strconv.FormatFloat(bounds[i], 'g', -1, 64)
+					} else {
+						bucketTags["le"] = "+Inf"
+					}
+
+					if i < len(rowData.ExemplarsPerBucket) {
+						if exemplar := rowData.ExemplarsPerBucket[i]; exemplar != nil {
+							if sc, ok := exemplar.Attachments[metricdata.AttachmentKeySpanContext].(trace.SpanContext); ok {
+								bucketTags[TraceIDField] = sc.TraceID.String()
+								bucketTags[SpanIDField] = sc.SpanID.String()
+							}
+						}
+					}
+
+					addMetric(
+						fmt.Sprintf("%s/bucket", viewData.View.Name),
+						float64(bucketCount),
+						bucketTags)
+				}
 			}
 		}
 	}